@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+)
+
+// Secret is a string value that Alertmanager reads a credential from, e.g. a
+// PagerDuty service key or a Slack webhook URL. It marshals as a plain
+// string: the rendered YAML is the live alertmanager.yaml Alertmanager loads,
+// not a display payload, so it must carry the real value.
+type Secret string
+
+// Config is the top-level configuration for Alertmanager's config files.
+type Config struct {
+	Global        *GlobalConfig   `yaml:"global,omitempty" json:"global,omitempty"`
+	Route         *Route          `yaml:"route,omitempty" json:"route,omitempty"`
+	InhibitRules  []*InhibitRule  `yaml:"inhibit_rules,omitempty" json:"inhibit_rules,omitempty"`
+	Receivers     []*Receiver     `yaml:"receivers,omitempty" json:"receivers,omitempty"`
+	TimeIntervals []*TimeInterval `yaml:"time_intervals,omitempty" json:"time_intervals,omitempty"`
+	Templates     []string        `yaml:"templates" json:"templates"`
+}
+
+// GlobalConfig holds values that are used across all other configuration
+// settings.
+type GlobalConfig struct {
+	PagerdutyURL string `yaml:"pagerduty_url,omitempty" json:"pagerduty_url,omitempty"`
+}
+
+// Route is a node in the routing tree that specifies which receiver should
+// handle alerts matching its selectors.
+type Route struct {
+	Receiver string `yaml:"receiver,omitempty" json:"receiver,omitempty"`
+
+	GroupBy []string `yaml:"group_by,omitempty" json:"group_by,omitempty"`
+
+	Match    map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	MatchRE  map[string]string `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+	Matchers []string          `yaml:"matchers,omitempty" json:"matchers,omitempty"`
+
+	Continue bool `yaml:"continue,omitempty" json:"continue,omitempty"`
+
+	Routes []*Route `yaml:"routes,omitempty" json:"routes,omitempty"`
+
+	GroupWait      *model.Duration `yaml:"group_wait,omitempty" json:"group_wait,omitempty"`
+	GroupInterval  *model.Duration `yaml:"group_interval,omitempty" json:"group_interval,omitempty"`
+	RepeatInterval *model.Duration `yaml:"repeat_interval,omitempty" json:"repeat_interval,omitempty"`
+
+	MuteTimeIntervals []string `yaml:"mute_time_intervals,omitempty" json:"mute_time_intervals,omitempty"`
+}
+
+// InhibitRule mirrors Alertmanager's inhibition rule: while an alert matching
+// SourceMatch/SourceMatchRE is firing, any alert matching TargetMatch/TargetMatchRE
+// that shares the same values for all of EqualLabels is suppressed.
+type InhibitRule struct {
+	SourceMatch   map[string]string `yaml:"source_match,omitempty" json:"source_match,omitempty"`
+	SourceMatchRE map[string]string `yaml:"source_match_re,omitempty" json:"source_match_re,omitempty"`
+	TargetMatch   map[string]string `yaml:"target_match,omitempty" json:"target_match,omitempty"`
+	TargetMatchRE map[string]string `yaml:"target_match_re,omitempty" json:"target_match_re,omitempty"`
+	Equal         []string          `yaml:"equal,omitempty" json:"equal,omitempty"`
+}
+
+// Receiver configuration provides configuration on how to contact a receiver.
+type Receiver struct {
+	Name string `yaml:"name" json:"name"`
+
+	PagerdutyConfigs []*PagerdutyConfig `yaml:"pagerduty_configs,omitempty" json:"pagerduty_configs,omitempty"`
+	SlackConfigs     []*SlackConfig     `yaml:"slack_configs,omitempty" json:"slack_configs,omitempty"`
+	EmailConfigs     []*EmailConfig     `yaml:"email_configs,omitempty" json:"email_configs,omitempty"`
+	WebhookConfigs   []*WebhookConfig   `yaml:"webhook_configs,omitempty" json:"webhook_configs,omitempty"`
+	WechatConfigs    []*WechatConfig    `yaml:"wechat_configs,omitempty" json:"wechat_configs,omitempty"`
+}
+
+// PagerdutyConfig configures notifications via PagerDuty.
+type PagerdutyConfig struct {
+	ServiceKey  Secret `yaml:"service_key,omitempty" json:"service_key,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// SlackConfig configures notifications via Slack.
+type SlackConfig struct {
+	APIURL    Secret `yaml:"api_url,omitempty" json:"api_url,omitempty"`
+	Channel   string `yaml:"channel,omitempty" json:"channel,omitempty"`
+	Text      string `yaml:"text,omitempty" json:"text,omitempty"`
+	Title     string `yaml:"title,omitempty" json:"title,omitempty"`
+	TitleLink string `yaml:"title_link,omitempty" json:"title_link,omitempty"`
+	Color     string `yaml:"color,omitempty" json:"color,omitempty"`
+}
+
+// EmailConfig configures notifications via mail.
+type EmailConfig struct {
+	To           string            `yaml:"to,omitempty" json:"to,omitempty"`
+	From         string            `yaml:"from,omitempty" json:"from,omitempty"`
+	Smarthost    string            `yaml:"smarthost,omitempty" json:"smarthost,omitempty"`
+	AuthUsername string            `yaml:"auth_username,omitempty" json:"auth_username,omitempty"`
+	AuthPassword Secret            `yaml:"auth_password,omitempty" json:"auth_password,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	HTML         string            `yaml:"html,omitempty" json:"html,omitempty"`
+	RequireTLS   *bool             `yaml:"require_tls,omitempty" json:"require_tls,omitempty"`
+}
+
+// WebhookConfig configures notifications via a generic webhook.
+type WebhookConfig struct {
+	URL          string      `yaml:"url" json:"url"`
+	HTTPConfig   *HTTPConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	SendResolved *bool       `yaml:"send_resolved,omitempty" json:"send_resolved,omitempty"`
+	MaxAlerts    int32       `yaml:"max_alerts,omitempty" json:"max_alerts,omitempty"`
+}
+
+// HTTPConfig configures how Alertmanager talks to a receiver's endpoint,
+// mirroring upstream Alertmanager's commoncfg.HTTPClientConfig.
+type HTTPConfig struct {
+	BasicAuth       *BasicAuth `yaml:"basic_auth,omitempty" json:"basic_auth,omitempty"`
+	BearerToken     Secret     `yaml:"bearer_token,omitempty" json:"bearer_token,omitempty"`
+	BearerTokenFile string     `yaml:"bearer_token_file,omitempty" json:"bearer_token_file,omitempty"`
+	TLSConfig       *TLSConfig `yaml:"tls_config,omitempty" json:"tls_config,omitempty"`
+	ProxyURL        string     `yaml:"proxy_url,omitempty" json:"proxy_url,omitempty"`
+}
+
+// BasicAuth configures HTTP basic authentication for a receiver endpoint.
+type BasicAuth struct {
+	Username string `yaml:"username" json:"username"`
+	Password Secret `yaml:"password,omitempty" json:"password,omitempty"`
+}
+
+// TLSConfig configures the TLS settings used when talking to a receiver's
+// endpoint, e.g. for mTLS to a webhook bridge.
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+}
+
+// WechatConfig configures notifications via WeChat Work (企业微信) app
+// messages.
+type WechatConfig struct {
+	CorpID    string `yaml:"corp_id,omitempty" json:"corp_id,omitempty"`
+	AgentID   string `yaml:"agent_id,omitempty" json:"agent_id,omitempty"`
+	APISecret Secret `yaml:"api_secret,omitempty" json:"api_secret,omitempty"`
+	Message   string `yaml:"message,omitempty" json:"message,omitempty"`
+	ToUser    string `yaml:"to_user,omitempty" json:"to_user,omitempty"`
+	ToParty   string `yaml:"to_party,omitempty" json:"to_party,omitempty"`
+	ToTag     string `yaml:"to_tag,omitempty" json:"to_tag,omitempty"`
+}
+
+// TimeInterval is a named set of time ranges that alert routes can reference
+// via mute_time_intervals to suppress notifications during a maintenance
+// window without having to repeat the schedule on every route.
+type TimeInterval struct {
+	Name          string      `yaml:"name" json:"name"`
+	TimeIntervals []TimeRange `yaml:"time_intervals" json:"time_intervals"`
+}
+
+// TimeRange is a single recurring window within a TimeInterval; all set
+// fields must match for a given instant to fall inside the range.
+type TimeRange struct {
+	Times       []TimeRangeItem `yaml:"times,omitempty" json:"times,omitempty"`
+	Weekdays    []string        `yaml:"weekdays,omitempty" json:"weekdays,omitempty"`
+	DaysOfMonth []string        `yaml:"days_of_month,omitempty" json:"days_of_month,omitempty"`
+	Months      []string        `yaml:"months,omitempty" json:"months,omitempty"`
+	Years       []string        `yaml:"years,omitempty" json:"years,omitempty"`
+}
+
+// TimeRangeItem is a start_time/end_time pair in HH:MM format.
+type TimeRangeItem struct {
+	StartTime string `yaml:"start_time" json:"start_time"`
+	EndTime   string `yaml:"end_time" json:"end_time"`
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("%+v", *c.Route)
+}
@@ -0,0 +1,130 @@
+package configsyncer
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/rancher/pkg/controllers/user/alert/common"
+	alertconfig "github.com/rancher/rancher/pkg/controllers/user/alert/config"
+	monitorutil "github.com/rancher/rancher/pkg/monitoring"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// alertmanagerConfigManagedLabel marks the AlertmanagerConfig CRs rancher
+// owns so garbage collection never touches one a user hand-authored.
+const alertmanagerConfigManagedLabel = "alerting.cattle.io/managed-by"
+const alertmanagerConfigManagedValue = "rancher-configsyncer"
+
+// supportsAlertmanagerConfig reports whether the prometheus-operator CRD set
+// installed in the cluster understands AlertmanagerConfig. It's probed once
+// in NewConfigSyncer and cached in alertmanagerConfigSupported: flipping
+// operator versions mid-cluster isn't something sync() needs to notice on
+// every reconcile.
+func (d *ConfigSyncer) supportsAlertmanagerConfig() bool {
+	return d.alertmanagerConfigSupported
+}
+
+// syncProjectAlertmanagerConfigs renders one AlertmanagerConfig CR per
+// project that has at least one receiver, scoped to the project's monitoring
+// namespace with a route matched on the project's alert groups, instead of
+// folding the project's routes/receivers into the shared alertmanager.yaml
+// secret. Projects with no receivers are skipped so sync() can garbage
+// collect a CR left behind by a project that no longer alerts. timeIntervals
+// is the same set rendered into the shared secret's top-level time_intervals
+// -- a project rule referencing one via MuteTimeIntervals needs its
+// definition in its own CR too, since the operator validates each
+// AlertmanagerConfig independently of the shared config.
+func (d *ConfigSyncer) syncProjectAlertmanagerConfigs(projectGroups map[string]map[string][]*v3.ProjectAlertRule, keys []string, notifiers []*v3.Notifier, timeIntervals []*alertconfig.TimeInterval) error {
+	liveNamespaces := map[string]bool{}
+
+	for _, projectID := range keys {
+		groups := projectGroups[projectID]
+		var groupIDs []string
+		for groupID := range groups {
+			groupIDs = append(groupIDs, groupID)
+		}
+		sort.Strings(groupIDs)
+
+		_, projectName := ref.Parse(projectID)
+		_, namespace := monitorutil.ProjectMonitoringInfo(projectName)
+
+		amConfig := d.operatorCRDManager.GetDefaultAlertmanagerConfig(namespace, projectName)
+		amConfig.Labels = map[string]string{alertmanagerConfigManagedLabel: alertmanagerConfigManagedValue}
+
+		config := &alertconfig.Config{}
+		config.Route = &alertconfig.Route{Routes: []*alertconfig.Route{}}
+
+		for _, groupID := range groupIDs {
+			rules := groups[groupID]
+			_, groupName := ref.Parse(groupID)
+			group, err := d.projectAlertGroupLister.Get(projectName, groupName)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "get project alert group %s:%s", projectName, groupName)
+			}
+			if group == nil {
+				continue
+			}
+
+			d.addInhibitRules(config, group.Spec.InhibitRules)
+
+			receiver := &alertconfig.Receiver{Name: groupID}
+			if !d.addRecipients(notifiers, receiver, group.Spec.Recipients) {
+				continue
+			}
+			config.Receivers = append(config.Receivers, receiver)
+
+			r1 := d.newRoute(map[string]string{"group_id": groupID}, nil, nil, nil, defaultGroupWait, defaultRepeatInterval, defaultGroupInterval)
+			for _, alert := range rules {
+				if alert.Status.AlertState == "inactive" {
+					continue
+				}
+				if alert.Spec.PodRule != nil || alert.Spec.WorkloadRule != nil || alert.Spec.MetricRule != nil {
+					d.addRule(common.GetRuleID(groupID, alert.Name), r1, alert.Spec.CommonRuleField)
+				}
+			}
+			d.appendRoute(config.Route, r1)
+		}
+
+		if len(config.Receivers) == 0 {
+			continue
+		}
+
+		amConfig.Receivers = config.Receivers
+		amConfig.Route = config.Route
+		amConfig.InhibitRules = config.InhibitRules
+		amConfig.TimeIntervals = timeIntervals
+
+		if err := d.operatorCRDManager.SyncAlertmanagerConfig(amConfig); err != nil {
+			return errors.Wrapf(err, "sync AlertmanagerConfig for project %s", projectID)
+		}
+		liveNamespaces[namespace] = true
+	}
+
+	return d.gcOrphanedAlertmanagerConfigs(liveNamespaces)
+}
+
+// gcOrphanedAlertmanagerConfigs removes rancher-managed AlertmanagerConfig
+// CRs in namespaces that no longer correspond to a project with receivers,
+// e.g. after the last project alert group is deleted. It never touches a CR
+// missing alertmanagerConfigManagedLabel.
+func (d *ConfigSyncer) gcOrphanedAlertmanagerConfigs(liveNamespaces map[string]bool) error {
+	managed, err := d.operatorCRDManager.ListManagedAlertmanagerConfigs(alertmanagerConfigManagedLabel, alertmanagerConfigManagedValue)
+	if err != nil {
+		return errors.Wrapf(err, "list managed AlertmanagerConfigs")
+	}
+
+	for _, amConfig := range managed {
+		if liveNamespaces[amConfig.Namespace] {
+			continue
+		}
+		logrus.Infof("Garbage collecting orphaned AlertmanagerConfig %s/%s", amConfig.Namespace, amConfig.Name)
+		if err := d.operatorCRDManager.DeleteAlertmanagerConfig(amConfig.Namespace, amConfig.Name); err != nil {
+			return errors.Wrapf(err, "delete orphaned AlertmanagerConfig %s/%s", amConfig.Namespace, amConfig.Name)
+		}
+	}
+
+	return nil
+}
@@ -35,18 +35,28 @@ var (
 	defaultRepeatInterval = 10
 )
 
+// pagerdutyEventsURL is PagerDuty's v1 Events API endpoint, used both as the
+// global default in Config and to dry-run a PagerDuty receiver in
+// TestNotifier.
+const pagerdutyEventsURL = "https://events.pagerduty.com/generic/2010-04-15/create_event.json"
+
 func NewConfigSyncer(ctx context.Context, cluster *config.UserContext, alertManager *manager.AlertManager, operatorCRDManager *manager.PromOperatorCRDManager) *ConfigSyncer {
-	return &ConfigSyncer{
+	d := &ConfigSyncer{
 		secretsGetter:           cluster.Core,
 		clusterAlertGroupLister: cluster.Management.Management.ClusterAlertGroups(cluster.ClusterName).Controller().Lister(),
 		projectAlertGroupLister: cluster.Management.Management.ProjectAlertGroups("").Controller().Lister(),
 		clusterAlertRuleLister:  cluster.Management.Management.ClusterAlertRules(cluster.ClusterName).Controller().Lister(),
 		projectAlertRuleLister:  cluster.Management.Management.ProjectAlertRules("").Controller().Lister(),
 		notifierLister:          cluster.Management.Management.Notifiers(cluster.ClusterName).Controller().Lister(),
+		timeIntervalLister:      cluster.Management.Management.TimeIntervals(cluster.ClusterName).Controller().Lister(),
 		clusterName:             cluster.ClusterName,
 		alertManager:            alertManager,
 		operatorCRDManager:      operatorCRDManager,
 	}
+	d.alertmanagerConfigSupported = operatorCRDManager != nil && operatorCRDManager.HasAlertmanagerConfigCRD()
+	d.debouncer = newDebouncer(d.sync)
+	go d.debouncer.run(ctx)
+	return d
 }
 
 type ConfigSyncer struct {
@@ -56,33 +66,51 @@ type ConfigSyncer struct {
 	projectAlertRuleLister  v3.ProjectAlertRuleLister
 	clusterAlertRuleLister  v3.ClusterAlertRuleLister
 	notifierLister          v3.NotifierLister
+	timeIntervalLister      v3.TimeIntervalLister
 	clusterName             string
 	alertManager            *manager.AlertManager
 	operatorCRDManager      *manager.PromOperatorCRDManager
+	debouncer               *debouncer
+
+	// alertmanagerConfigSupported caches whether the cluster's
+	// prometheus-operator understands AlertmanagerConfig, probed once at
+	// construction rather than on every sync().
+	alertmanagerConfigSupported bool
 }
 
+// ProjectGroupSync, ClusterGroupSync, ProjectRuleSync, ClusterRuleSync and
+// NotifierSync all enqueue the same debounced rebuild rather than calling
+// sync() directly, so N events within debounceInterval collapse into one
+// list-everything-and-rewrite-the-secret pass instead of one per event.
+
 func (d *ConfigSyncer) ProjectGroupSync(key string, alert *v3.ProjectAlertGroup) (runtime.Object, error) {
-	return nil, d.sync()
+	d.debouncer.enqueue()
+	return nil, nil
 }
 
 func (d *ConfigSyncer) ClusterGroupSync(key string, alert *v3.ClusterAlertGroup) (runtime.Object, error) {
-	return nil, d.sync()
+	d.debouncer.enqueue()
+	return nil, nil
 }
 
 func (d *ConfigSyncer) ProjectRuleSync(key string, alert *v3.ProjectAlertRule) (runtime.Object, error) {
-	return nil, d.sync()
+	d.debouncer.enqueue()
+	return nil, nil
 }
 
 func (d *ConfigSyncer) ClusterRuleSync(key string, alert *v3.ClusterAlertRule) (runtime.Object, error) {
-	return nil, d.sync()
+	d.debouncer.enqueue()
+	return nil, nil
 }
 
 func (d *ConfigSyncer) NotifierSync(key string, alert *v3.Notifier) (runtime.Object, error) {
-	return nil, d.sync()
+	d.debouncer.enqueue()
+	return nil, nil
 }
 
 //sync: update the secret which store the configuration of alertmanager given the latest configured notifiers and alerts rules.
 //For each alert, it will generate a route and a receiver in the alertmanager's configuration file, for metric rules it will update operator crd also.
+//When the cluster's prometheus-operator supports AlertmanagerConfig, project-level routes/receivers are rendered as per-project CRs instead of being folded into the shared secret.
 func (d *ConfigSyncer) sync() error {
 	if d.alertManager.IsDeploy == false {
 		return nil
@@ -106,6 +134,11 @@ func (d *ConfigSyncer) sync() error {
 		return errors.Wrapf(err, "List project alert rules")
 	}
 
+	timeIntervals, err := d.timeIntervalLister.List("", labels.NewSelector())
+	if err != nil {
+		return errors.Wrapf(err, "List time intervals")
+	}
+
 	cAlertsMap := map[string][]*v3.ClusterAlertRule{}
 	cAlertsKey := []string{}
 	for _, alert := range clusterAlertRules {
@@ -146,14 +179,24 @@ func (d *ConfigSyncer) sync() error {
 	}
 
 	config := manager.GetAlertManagerDefaultConfig()
-	config.Global.PagerdutyURL = "https://events.pagerduty.com/generic/2010-04-15/create_event.json"
+	config.Global.PagerdutyURL = pagerdutyEventsURL
+	config.TimeIntervals = toAlertmanagerTimeIntervals(timeIntervals)
 
 	if err = d.addClusterAlert2Config(config, cAlertsMap, cAlertsKey, notifiers); err != nil {
 		return err
 	}
 
-	if err = d.addProjectAlert2Config(config, pAlertsMap, pAlertsKey, notifiers); err != nil {
-		return err
+	// Cluster-level rules always stay in the shared secret -- only a
+	// project's own routes/receivers move into a namespaced CR, and only
+	// when the installed prometheus-operator CRD set understands it.
+	if d.supportsAlertmanagerConfig() {
+		if err = d.syncProjectAlertmanagerConfigs(pAlertsMap, pAlertsKey, notifiers, config.TimeIntervals); err != nil {
+			return err
+		}
+	} else {
+		if err = d.addProjectAlert2Config(config, pAlertsMap, pAlertsKey, notifiers); err != nil {
+			return err
+		}
 	}
 
 	data, err := yaml.Marshal(config)
@@ -178,6 +221,7 @@ func (d *ConfigSyncer) sync() error {
 		if err != nil {
 			return errors.Wrapf(err, "Update secrets")
 		}
+		secretUpdateTotal.Inc()
 
 	} else {
 		logrus.Debug("The config stay the same, will not update the secret")
@@ -286,11 +330,13 @@ func (d *ConfigSyncer) addProjectAlert2Config(config *alertconfig.Config, projec
 
 			receiver := &alertconfig.Receiver{Name: groupID}
 
+			d.addInhibitRules(config, group.Spec.InhibitRules)
+
 			exist := d.addRecipients(notifiers, receiver, group.Spec.Recipients)
 
 			if exist {
 				config.Receivers = append(config.Receivers, receiver)
-				r1 := d.newRoute(map[string]string{"group_id": groupID}, defaultGroupWait, defaultRepeatInterval, defaultGroupInterval)
+				r1 := d.newRoute(map[string]string{"group_id": groupID}, nil, nil, nil, defaultGroupWait, defaultRepeatInterval, defaultGroupInterval)
 
 				for _, alert := range rules {
 					if alert.Status.AlertState == "inactive" {
@@ -327,11 +373,13 @@ func (d *ConfigSyncer) addClusterAlert2Config(config *alertconfig.Config, alerts
 			continue
 		}
 
+		d.addInhibitRules(config, group.Spec.InhibitRules)
+
 		exist := d.addRecipients(notifiers, receiver, group.Spec.Recipients)
 
 		if exist {
 			config.Receivers = append(config.Receivers, receiver)
-			r1 := d.newRoute(map[string]string{"group_id": groupID}, defaultGroupWait, defaultRepeatInterval, defaultGroupInterval)
+			r1 := d.newRoute(map[string]string{"group_id": groupID}, nil, nil, nil, defaultGroupWait, defaultRepeatInterval, defaultGroupInterval)
 			for _, alert := range groupRules {
 				if alert.Status.AlertState == "inactive" {
 					continue
@@ -339,7 +387,7 @@ func (d *ConfigSyncer) addClusterAlert2Config(config *alertconfig.Config, alerts
 				ruleID := common.GetRuleID(groupID, alert.Name)
 
 				if alert.Spec.EventRule != nil {
-					r2 := d.newRoute(map[string]string{"alert_type": "event", "rule_id": ruleID}, defaultGroupWait, defaultRepeatInterval, eventGroupInterval)
+					r2 := d.newRoute(map[string]string{"alert_type": "event", "rule_id": ruleID}, nil, nil, nil, defaultGroupWait, defaultRepeatInterval, eventGroupInterval)
 					d.appendRoute(r1, r2) //todo: better not overwrite interval for each, if the interval is same as above, should not add interval field
 				}
 
@@ -356,14 +404,66 @@ func (d *ConfigSyncer) addClusterAlert2Config(config *alertconfig.Config, alerts
 }
 
 func (d *ConfigSyncer) addRule(ruleID string, route *alertconfig.Route, comm v3.CommonRuleField) {
-	r2 := d.newRoute(map[string]string{"rule_id": ruleID}, comm.GroupWaitSeconds, comm.GroupIntervalSeconds, comm.RepeatIntervalSeconds)
+	r2 := d.newRoute(map[string]string{"rule_id": ruleID}, toAlertmanagerMatchers(comm.Matchers), comm.GroupBy, comm.MuteTimeIntervals, comm.GroupWaitSeconds, comm.GroupIntervalSeconds, comm.RepeatIntervalSeconds)
 	d.appendRoute(route, r2)
 }
 
-func (d *ConfigSyncer) newRoute(match map[string]string, groupWait, groupInterval, repeatInterval int) *alertconfig.Route {
+// toAlertmanagerMatchers renders label-based matchers in Alertmanager's
+// matcher syntax (label="value", label!="value", label=~"regex",
+// label!~"regex"). addRule emits them on the rule's route next to its
+// rule_id match, so they narrow which of the rule's alerts the route takes.
+func toAlertmanagerMatchers(matchers []v3.Matcher) []string {
+	var out []string
+	for _, m := range matchers {
+		var op string
+		switch {
+		case !m.Regex && m.IsEqual:
+			op = "="
+		case !m.Regex && !m.IsEqual:
+			op = "!="
+		case m.Regex && m.IsEqual:
+			op = "=~"
+		default:
+			op = "!~"
+		}
+		out = append(out, fmt.Sprintf("%s%s%q", m.Name, op, m.Value))
+	}
+	return out
+}
+
+// toAlertmanagerTimeIntervals converts the cluster's TimeInterval CRDs into
+// the top-level time_intervals Alertmanager expects, so a maintenance
+// window only has to be defined once and can be referenced by name from any
+// rule's MuteTimeIntervals.
+func toAlertmanagerTimeIntervals(timeIntervals []*v3.TimeInterval) []*alertconfig.TimeInterval {
+	var out []*alertconfig.TimeInterval
+	for _, ti := range timeIntervals {
+		var ranges []alertconfig.TimeRange
+		for _, r := range ti.Spec.TimeIntervals {
+			var times []alertconfig.TimeRangeItem
+			for _, t := range r.Times {
+				times = append(times, alertconfig.TimeRangeItem{StartTime: t.StartTime, EndTime: t.EndTime})
+			}
+			ranges = append(ranges, alertconfig.TimeRange{
+				Times:       times,
+				Weekdays:    r.Weekdays,
+				DaysOfMonth: r.DaysOfMonth,
+				Months:      r.Months,
+				Years:       r.Years,
+			})
+		}
+		out = append(out, &alertconfig.TimeInterval{Name: ti.Name, TimeIntervals: ranges})
+	}
+	return out
+}
+
+func (d *ConfigSyncer) newRoute(match map[string]string, matchers []string, groupBy, muteTimeIntervals []string, groupWait, groupInterval, repeatInterval int) *alertconfig.Route {
 	route := &alertconfig.Route{
-		Receiver: match["group_id"],
-		Match:    match,
+		Receiver:          match["group_id"],
+		Match:             match,
+		Matchers:          matchers,
+		GroupBy:           groupBy,
+		MuteTimeIntervals: muteTimeIntervals,
 	}
 
 	gw := model.Duration(time.Duration(groupWait) * time.Second)
@@ -408,7 +508,10 @@ func (d *ConfigSyncer) addRecipients(notifiers []*v3.Notifier, receiver *alertco
 
 			} else if notifier.Spec.WebhookConfig != nil {
 				webhook := &alertconfig.WebhookConfig{
-					URL: notifier.Spec.WebhookConfig.URL,
+					URL:          notifier.Spec.WebhookConfig.URL,
+					HTTPConfig:   toWebhookHTTPConfig(notifier.Spec.WebhookConfig),
+					SendResolved: notifier.Spec.WebhookConfig.SendResolved,
+					MaxAlerts:    notifier.Spec.WebhookConfig.MaxAlerts,
 				}
 				if r.Recipient != "" {
 					webhook.URL = r.Recipient
@@ -448,6 +551,44 @@ func (d *ConfigSyncer) addRecipients(notifiers []*v3.Notifier, receiver *alertco
 				}
 				receiver.EmailConfigs = append(receiver.EmailConfigs, email)
 				receiverExist = true
+			} else if notifier.Spec.DingtalkConfig != nil {
+				// Alertmanager has no native DingTalk receiver: a DingTalk
+				// custom-robot webhook only understands DingTalk's own
+				// message format, not Alertmanager's. Delivery goes through
+				// an out-of-process bridge (e.g. prometheus-webhook-dingtalk)
+				// that translates Alertmanager's webhook_configs payload, so
+				// URL here is the bridge's endpoint rather than the robot
+				// webhook directly. The HMAC signing secret and @-mentions
+				// are configured per-target on the bridge itself, not
+				// carried per-alert through webhook_configs, so they can't
+				// be forwarded here -- warn rather than drop them silently.
+				dc := notifier.Spec.DingtalkConfig
+				if dc.Secret != "" || len(dc.AtMobiles) > 0 || len(dc.AtUserIDs) > 0 || dc.IsAtAll {
+					logrus.Warnf("notifier %s: DingTalk signing secret and @-mentions must be configured on the prometheus-webhook-dingtalk bridge target, not on the notifier -- ignoring Secret/AtMobiles/AtUserIDs/IsAtAll", notifier.Name)
+				}
+				dingtalk := &alertconfig.WebhookConfig{
+					URL: dc.URL,
+				}
+				if r.Recipient != "" {
+					dingtalk.URL = r.Recipient
+				}
+				receiver.WebhookConfigs = append(receiver.WebhookConfigs, dingtalk)
+				receiverExist = true
+			} else if notifier.Spec.WechatConfig != nil {
+				wechat := &alertconfig.WechatConfig{
+					CorpID:    notifier.Spec.WechatConfig.CorpID,
+					AgentID:   notifier.Spec.WechatConfig.AgentID,
+					APISecret: alertconfig.Secret(notifier.Spec.WechatConfig.APISecret),
+					Message:   `{{ template "rancher.title" . }} {{ if eq (index .Alerts 0).Labels.severity "critical" }}<font color="warning">critical</font>{{ else if eq (index .Alerts 0).Labels.severity "warning" }}<font color="comment">warning</font>{{ end }}`,
+					ToUser:    notifier.Spec.WechatConfig.ToUser,
+					ToParty:   notifier.Spec.WechatConfig.ToParty,
+					ToTag:     notifier.Spec.WechatConfig.ToTag,
+				}
+				if r.Recipient != "" {
+					wechat.ToUser = r.Recipient
+				}
+				receiver.WechatConfigs = append(receiver.WechatConfigs, wechat)
+				receiverExist = true
 			}
 
 		}
@@ -457,6 +598,61 @@ func (d *ConfigSyncer) addRecipients(notifiers []*v3.Notifier, receiver *alertco
 
 }
 
+// addInhibitRules translates the inhibition rules configured on an alert
+// group into alertconfig.InhibitRule entries and appends them to the
+// top-level config. Since config is rebuilt from scratch on every sync, this
+// naturally stays idempotent: stale rules never linger across rebuilds.
+func (d *ConfigSyncer) addInhibitRules(config *alertconfig.Config, rules []v3.AlertInhibitRule) {
+	for _, rule := range rules {
+		if len(rule.SourceMatch) == 0 || len(rule.TargetMatch) == 0 {
+			logrus.Debugf("Skip inhibit rule with empty source or target match")
+			continue
+		}
+
+		config.InhibitRules = append(config.InhibitRules, &alertconfig.InhibitRule{
+			SourceMatch: rule.SourceMatch,
+			TargetMatch: rule.TargetMatch,
+			Equal:       rule.EqualLabels,
+		})
+	}
+}
+
+// toWebhookHTTPConfig maps the auth/TLS/proxy fields on a webhook notifier
+// onto alertconfig's HTTPConfig. It returns nil when none of those fields
+// are set so an unauthenticated webhook keeps emitting the same minimal
+// webhook_configs entry as before.
+func toWebhookHTTPConfig(webhook *v3.WebhookConfig) *alertconfig.HTTPConfig {
+	var basicAuth *alertconfig.BasicAuth
+	if webhook.BasicAuthUsername != "" {
+		basicAuth = &alertconfig.BasicAuth{
+			Username: webhook.BasicAuthUsername,
+			Password: alertconfig.Secret(webhook.BasicAuthPassword),
+		}
+	}
+
+	var tlsConfig *alertconfig.TLSConfig
+	if webhook.TLSCAFile != "" || webhook.TLSCertFile != "" || webhook.TLSKeyFile != "" || webhook.TLSInsecureSkipVerify {
+		tlsConfig = &alertconfig.TLSConfig{
+			CAFile:             webhook.TLSCAFile,
+			CertFile:           webhook.TLSCertFile,
+			KeyFile:            webhook.TLSKeyFile,
+			InsecureSkipVerify: webhook.TLSInsecureSkipVerify,
+		}
+	}
+
+	if basicAuth == nil && tlsConfig == nil && webhook.BearerToken == "" && webhook.BearerTokenFile == "" && webhook.ProxyURL == "" {
+		return nil
+	}
+
+	return &alertconfig.HTTPConfig{
+		BasicAuth:       basicAuth,
+		BearerToken:     alertconfig.Secret(webhook.BearerToken),
+		BearerTokenFile: webhook.BearerTokenFile,
+		TLSConfig:       tlsConfig,
+		ProxyURL:        webhook.ProxyURL,
+	}
+}
+
 func includeProjectMetrics(projectAlerts []*v3.ProjectAlertRule) bool {
 	for _, v := range projectAlerts {
 		if v.Spec.MetricRule != nil {
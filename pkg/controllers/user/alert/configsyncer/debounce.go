@@ -0,0 +1,105 @@
+package configsyncer
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var (
+	rebuildTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "configsync_rebuild_total",
+		Help: "Total number of alertmanager config rebuilds performed by configsyncer.",
+	})
+	rebuildDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "configsync_rebuild_duration_seconds",
+		Help: "Time spent rebuilding the alertmanager config on a sync().",
+	})
+	secretUpdateTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "configsync_secret_update_total",
+		Help: "Total number of times configsyncer wrote a new alertmanager.yaml secret.",
+	})
+	syncErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "configsync_errors_total",
+		Help: "Total number of sync() errors encountered by configsyncer.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(rebuildTotal, rebuildDuration, secretUpdateTotal, syncErrorsTotal)
+}
+
+// debounceInterval is the window configsyncer coalesces rapid bursts of
+// ProjectRuleSync/ClusterRuleSync/NotifierSync/ProjectGroupSync/ClusterGroupSync
+// events -- a bulk edit or a controller resync storm -- into a single
+// sync() rebuild.
+const debounceInterval = 2 * time.Second
+
+// syncKey is the single item debouncer's workqueue ever holds. All five
+// handlers enqueue the same key, so the queue's own dedup (a key already
+// waiting or queued is not added a second time; one added while fn is
+// running is run once more afterwards) is what coalesces a burst of events
+// into one rebuild.
+const syncKey = "sync"
+
+// debouncer collapses the enqueue() calls that arrive within debounceInterval
+// of the first one of a burst into a single call to fn: a later enqueue() of
+// the already-waiting key keeps its earlier ready time, so fn runs
+// debounceInterval after the burst starts rather than after it goes quiet.
+// The queue is backed by a rate limiter so a failing fn is retried with
+// backoff instead of dropped until some unrelated event happens to fire
+// again.
+type debouncer struct {
+	queue workqueue.RateLimitingInterface
+	fn    func() error
+}
+
+func newDebouncer(fn func() error) *debouncer {
+	return &debouncer{
+		queue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		fn:    fn,
+	}
+}
+
+func (d *debouncer) enqueue() {
+	d.queue.AddAfter(syncKey, debounceInterval)
+}
+
+// run drains the queue until ctx is cancelled.
+func (d *debouncer) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		d.queue.ShutDown()
+	}()
+
+	for d.processNext() {
+	}
+}
+
+// processNext runs fn for the next queued key, retrying it through the
+// queue's rate limiter on failure and forgetting its backoff on success.
+// Returns false once the queue has been shut down.
+func (d *debouncer) processNext() bool {
+	key, shutdown := d.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer d.queue.Done(key)
+
+	start := time.Now()
+	err := d.fn()
+	rebuildTotal.Inc()
+	rebuildDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		syncErrorsTotal.Inc()
+		logrus.Errorf("configsyncer: failed to sync alertmanager config: %v", err)
+		d.queue.AddRateLimited(key)
+		return true
+	}
+
+	d.queue.Forget(key)
+	return true
+}
@@ -0,0 +1,59 @@
+package configsyncer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errFailedSync = errors.New("transient sync failure")
+
+func TestDebouncerCoalescesBurst(t *testing.T) {
+	var calls int32
+	d := newDebouncer(func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.run(ctx)
+
+	for i := 0; i < 10; i++ {
+		d.enqueue()
+	}
+
+	time.Sleep(debounceInterval + 500*time.Millisecond)
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected a burst of enqueue() calls to coalesce into 1 sync, got %d", got)
+	}
+}
+
+func TestDebouncerRetriesOnError(t *testing.T) {
+	var calls int32
+	d := newDebouncer(func() error {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			return errFailedSync
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.run(ctx)
+
+	d.enqueue()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(&calls) >= 2 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("expected a failed sync to be retried, got %d calls", atomic.LoadInt32(&calls))
+}
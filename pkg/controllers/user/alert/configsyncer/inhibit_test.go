@@ -0,0 +1,40 @@
+package configsyncer
+
+import (
+	"testing"
+
+	alertconfig "github.com/rancher/rancher/pkg/controllers/user/alert/config"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+)
+
+func TestAddInhibitRules(t *testing.T) {
+	d := &ConfigSyncer{}
+	config := &alertconfig.Config{}
+
+	rules := []v3.AlertInhibitRule{
+		{
+			SourceMatch: map[string]string{"severity": "critical"},
+			TargetMatch: map[string]string{"severity": "warning"},
+			EqualLabels: []string{"alertname"},
+		},
+		{
+			// No TargetMatch: must be skipped rather than emitted as a
+			// rule that inhibits everything.
+			SourceMatch: map[string]string{"severity": "critical"},
+		},
+	}
+
+	d.addInhibitRules(config, rules)
+
+	if len(config.InhibitRules) != 1 {
+		t.Fatalf("expected 1 inhibit rule, got %d", len(config.InhibitRules))
+	}
+
+	got := config.InhibitRules[0]
+	if got.SourceMatch["severity"] != "critical" || got.TargetMatch["severity"] != "warning" {
+		t.Fatalf("unexpected inhibit rule: %+v", got)
+	}
+	if len(got.Equal) != 1 || got.Equal[0] != "alertname" {
+		t.Fatalf("unexpected equal labels: %+v", got.Equal)
+	}
+}
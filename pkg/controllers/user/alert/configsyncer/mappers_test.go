@@ -0,0 +1,63 @@
+package configsyncer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestToAlertmanagerMatchers(t *testing.T) {
+	matchers := []v3.Matcher{
+		{Name: "severity", Value: "critical", IsEqual: true, Regex: false},
+		{Name: "severity", Value: "warning", IsEqual: false, Regex: false},
+		{Name: "pod", Value: "app-.*", IsEqual: true, Regex: true},
+		{Name: "pod", Value: "app-.*", IsEqual: false, Regex: true},
+	}
+
+	got := toAlertmanagerMatchers(matchers)
+	want := []string{
+		`severity="critical"`,
+		`severity!="warning"`,
+		`pod=~"app-.*"`,
+		`pod!~"app-.*"`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("toAlertmanagerMatchers() = %v, want %v", got, want)
+	}
+}
+
+func TestToAlertmanagerTimeIntervals(t *testing.T) {
+	timeIntervals := []*v3.TimeInterval{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "maintenance"},
+			Spec: v3.TimeIntervalSpec{
+				TimeIntervals: []v3.TimeRange{
+					{
+						Times:    []v3.TimeRangeItem{{StartTime: "00:00", EndTime: "06:00"}},
+						Weekdays: []string{"saturday", "sunday"},
+					},
+				},
+			},
+		},
+	}
+
+	out := toAlertmanagerTimeIntervals(timeIntervals)
+	if len(out) != 1 {
+		t.Fatalf("expected 1 time interval, got %d", len(out))
+	}
+	if out[0].Name != "maintenance" {
+		t.Fatalf("expected name 'maintenance', got %q", out[0].Name)
+	}
+	if len(out[0].TimeIntervals) != 1 || len(out[0].TimeIntervals[0].Times) != 1 {
+		t.Fatalf("unexpected ranges: %+v", out[0].TimeIntervals)
+	}
+	if out[0].TimeIntervals[0].Times[0].StartTime != "00:00" || out[0].TimeIntervals[0].Times[0].EndTime != "06:00" {
+		t.Fatalf("unexpected time range: %+v", out[0].TimeIntervals[0].Times[0])
+	}
+	if !reflect.DeepEqual(out[0].TimeIntervals[0].Weekdays, []string{"saturday", "sunday"}) {
+		t.Fatalf("unexpected weekdays: %v", out[0].TimeIntervals[0].Weekdays)
+	}
+}
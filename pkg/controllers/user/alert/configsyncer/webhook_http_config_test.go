@@ -0,0 +1,42 @@
+package configsyncer
+
+import (
+	"testing"
+
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+)
+
+func TestToWebhookHTTPConfigNilWhenUnconfigured(t *testing.T) {
+	cfg := toWebhookHTTPConfig(&v3.WebhookConfig{URL: "http://example.com/hook"})
+	if cfg != nil {
+		t.Fatalf("expected nil HTTPConfig for a webhook with no auth/TLS fields set, got %+v", cfg)
+	}
+}
+
+func TestToWebhookHTTPConfigBasicAuthAndTLS(t *testing.T) {
+	webhook := &v3.WebhookConfig{
+		URL:                   "https://example.com/hook",
+		BasicAuthUsername:     "admin",
+		BasicAuthPassword:     "s3cret",
+		TLSCAFile:             "/etc/ca.pem",
+		TLSInsecureSkipVerify: true,
+	}
+
+	cfg := toWebhookHTTPConfig(webhook)
+	if cfg == nil {
+		t.Fatal("expected a non-nil HTTPConfig")
+	}
+	if cfg.BasicAuth == nil || cfg.BasicAuth.Username != "admin" || string(cfg.BasicAuth.Password) != "s3cret" {
+		t.Fatalf("unexpected basic auth: %+v", cfg.BasicAuth)
+	}
+	if cfg.TLSConfig == nil || cfg.TLSConfig.CAFile != "/etc/ca.pem" || !cfg.TLSConfig.InsecureSkipVerify {
+		t.Fatalf("unexpected tls config: %+v", cfg.TLSConfig)
+	}
+}
+
+func TestToWebhookHTTPConfigBearerToken(t *testing.T) {
+	cfg := toWebhookHTTPConfig(&v3.WebhookConfig{URL: "https://example.com/hook", BearerToken: "token123"})
+	if cfg == nil || string(cfg.BearerToken) != "token123" {
+		t.Fatalf("expected bearer token to be carried through, got %+v", cfg)
+	}
+}